@@ -4,16 +4,46 @@ Package oneconf will populate a central configuration struct wil data from TOML
 package oneconf
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
 )
 
+// watchDebounce is how long WatchTOML waits after the last filesystem
+// event before reloading, so a burst of writes only triggers one reload.
+const watchDebounce = 100 * time.Millisecond
+
+// scan walks the fields of v, as scanE, but exits the process on error to
+// preserve the historical behavior of LoadDefaults/LoadTOML/LoadEnv/LoadFlags.
 func scan(v any, chain []string, cb func(name, kind string, tag reflect.StructTag, chain []string) string) {
+	if err := scanE(v, chain, cb); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// durationType and timeType let scanE special-case time.Duration and
+// time.Time before falling back to their underlying Kind (Int64 and
+// Struct, respectively).
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// scanE walks the fields of v, calling cb for every leaf field to obtain the
+// raw value to apply, and returns an error wrapped with the field's path
+// (e.g. "D.F: invalid value \"abc\" for float64") on the first parse failure.
+func scanE(v any, chain []string, cb func(name, kind string, tag reflect.StructTag, chain []string) string) error {
 	rt := reflect.TypeOf(v).Elem()
 	rv := reflect.ValueOf(v).Elem()
 
@@ -21,26 +51,52 @@ func scan(v any, chain []string, cb func(name, kind string, tag reflect.StructTa
 		field := rt.Field(i).Name
 		// vt := rv.Field(i).Type()
 		vv := rv.Field(i).Addr()
+		tag := rt.Field(i).Tag
+		ft := rt.Field(i).Type
+
+		if ft == durationType {
+			if val := cb(field, "duration", tag, chain); val != "" {
+				d, err := time.ParseDuration(val)
+				if err != nil {
+					return fieldErr(chain, field, val, "time.Duration")
+				}
+				rv.Field(i).SetInt(int64(d))
+			}
+			continue
+		}
+
+		if ft == timeType {
+			if val := cb(field, "time", tag, chain); val != "" {
+				t, err := time.Parse(time.RFC3339, val)
+				if err != nil {
+					return fieldErr(chain, field, val, "time.Time")
+				}
+				rv.Field(i).Set(reflect.ValueOf(t))
+			}
+			continue
+		}
+
+		kind := rv.Field(i).Kind()
 
-		switch rv.Field(i).Kind() {
+		switch kind {
 		case reflect.String:
 			// fmt.Printf("%s : %s(%s)-%v\n", field, vv, vt, tag)
-			if val := cb(field, "string", rt.Field(i).Tag, chain); val != "" {
+			if val := cb(field, "string", tag, chain); val != "" {
 				rv.Field(i).SetString(val)
 			}
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			// fmt.Printf("%s : %s(%s)-%s\n", field, vv, vt, tag)
-			if val := cb(field, "int", rt.Field(i).Tag, chain); val != "" {
-				if a, err := strconv.ParseInt(val, 10, 64); err == nil {
-					rv.Field(i).SetInt(a)
-					continue
+			if val := cb(field, "int", tag, chain); val != "" {
+				a, err := strconv.ParseInt(val, 10, 64)
+				if err != nil {
+					return fieldErr(chain, field, val, kind.String())
 				}
-				fmt.Printf("Invalid '%v' while setting value of %s\n", val, field)
-				os.Exit(1)
+				rv.Field(i).SetInt(a)
 			}
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 			// fmt.Printf("%s : %s(%s)-%s\n", field, vv, vt, tag)
-			if val := cb(field, "uint", rt.Field(i).Tag, chain); val != "" {
+			if val := cb(field, "uint", tag, chain); val != "" {
+				raw := val
 				base := 10
 				if strings.HasPrefix(val, "0x") {
 					base = 16
@@ -54,70 +110,428 @@ func scan(v any, chain []string, cb func(name, kind string, tag reflect.StructTa
 					base = 2
 					val = val[2:]
 				}
-				if a, err := strconv.ParseUint(val, base, 64); err == nil {
-					rv.Field(i).SetUint(a)
-					continue
+				a, err := strconv.ParseUint(val, base, 64)
+				if err != nil {
+					return fieldErr(chain, field, raw, kind.String())
 				}
-				fmt.Printf("Invalid '%v' while setting value of %s\n", val, field)
-				os.Exit(1)
+				rv.Field(i).SetUint(a)
 			}
 		case reflect.Float32, reflect.Float64:
 			// fmt.Printf("%s : %s(%s)-%s\n", field, vv, vt, tag)
-			if val := cb(field, "float", rt.Field(i).Tag, chain); val != "" {
-				if a, err := strconv.ParseFloat(val, 64); err == nil {
-					rv.Field(i).SetFloat(a)
-					continue
+			if val := cb(field, "float", tag, chain); val != "" {
+				a, err := strconv.ParseFloat(val, 64)
+				if err != nil {
+					return fieldErr(chain, field, val, kind.String())
 				}
-				fmt.Printf("Invalid '%v' while setting value of %s\n", val, field)
-				os.Exit(1)
+				rv.Field(i).SetFloat(a)
 			}
 		case reflect.Bool:
 			// fmt.Printf("%s : %s(%s)-%s\n", field, vv, vt, tag)
-			if val := cb(field, "bool", rt.Field(i).Tag, chain); val != "" {
-				if a, err := strconv.ParseBool(val); err == nil {
-					rv.Field(i).SetBool(a)
-					continue
+			if val := cb(field, "bool", tag, chain); val != "" {
+				a, err := strconv.ParseBool(val)
+				if err != nil {
+					return fieldErr(chain, field, val, kind.String())
+				}
+				rv.Field(i).SetBool(a)
+			}
+		case reflect.Slice:
+			// fmt.Printf("%s : %s(%s)-%s\n", field, vv, vt, tag)
+			if val := cb(field, "slice", tag, chain); val != "" {
+				if err := setSlice(rv.Field(i), splitSep(val, tag)); err != nil {
+					return fieldErr(chain, field, val, sliceTypeName(ft))
+				}
+			}
+		case reflect.Map:
+			// fmt.Printf("%s : %s(%s)-%s\n", field, vv, vt, tag)
+			if val := cb(field, "map", tag, chain); val != "" {
+				if err := setMap(rv.Field(i), splitSep(val, tag)); err != nil {
+					return fieldErr(chain, field, val, "map[string]string")
 				}
-				fmt.Printf("Invalid '%v' while setting value of %s\n", val, field)
-				os.Exit(1)
 			}
 		case reflect.Struct:
 			// fmt.Printf("%s : it is %s\n", field, vt)
-			var step []string
-			copy(step, chain)
-			step = append(step, field)
-			scan(vv.Interface(), step, cb)
+			step := append(append([]string{}, chain...), field)
+			if err := scanE(vv.Interface(), step, cb); err != nil {
+				return err
+			}
 
 			// default:
 			// 	fmt.Println("ignoring: ", vt)
 		}
 	}
+
+	return nil
+}
+
+// fieldErr formats a scanE parse failure, e.g. "D.F: invalid value \"abc\" for float64".
+func fieldErr(chain []string, field, val, typeName string) error {
+	path := strings.Join(append(append([]string{}, chain...), field), ".")
+	return fmt.Errorf("%s: invalid value %q for %s", path, val, typeName)
+}
+
+// splitSep splits val on the separator given by the field's "sep" tag,
+// defaulting to ",".
+func splitSep(val string, tag reflect.StructTag) []string {
+	sep := tag.Get("sep")
+	if sep == "" {
+		sep = ","
+	}
+
+	return strings.Split(val, sep)
+}
+
+// sliceTypeName returns the Go type name used in error messages for an
+// unsupported or malformed slice field.
+func sliceTypeName(t reflect.Type) string {
+	return "[]" + t.Elem().Kind().String()
+}
+
+// setSlice fills a []string, []int or []float64 field from parts; any
+// other slice element type is left untouched.
+func setSlice(field reflect.Value, parts []string) error {
+	switch field.Interface().(type) {
+	case []string:
+		field.Set(reflect.ValueOf(parts))
+	case []int:
+		ints := make([]int, len(parts))
+		for i, p := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil {
+				return err
+			}
+			ints[i] = n
+		}
+		field.Set(reflect.ValueOf(ints))
+	case []float64:
+		floats := make([]float64, len(parts))
+		for i, p := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return err
+			}
+			floats[i] = f
+		}
+		field.Set(reflect.ValueOf(floats))
+	}
+
+	return nil
+}
+
+// setMap fills a map[string]string field from "k1=v1,k2=v2"-style pairs;
+// any other map type is left untouched.
+func setMap(field reflect.Value, pairs []string) error {
+	if _, ok := field.Interface().(map[string]string); !ok {
+		return nil
+	}
+
+	m := map[string]string{}
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid pair %q", pair)
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+
+	field.Set(reflect.ValueOf(m))
+
+	return nil
 }
 
 // LoadDefaults will set all default to the structure fields
 func LoadDefaults(c any) {
+	if err := LoadDefaultsE(c); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
 
+// LoadDefaultsE is like LoadDefaults but returns an error instead of
+// exiting the process.
+func LoadDefaultsE(c any) error {
 	cb := func(name, kind string, tags reflect.StructTag, chain []string) string {
 		return tags.Get("default")
 	}
 
-	scan(c, []string{}, cb)
+	return scanE(c, []string{}, cb)
 }
 
 // LoadTOML set structure value to the TOML file content
 func LoadTOML(c any, file string) {
+	if err := LoadTOMLE(c, file); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// LoadTOMLE is like LoadTOML but returns an error instead of exiting.
+func LoadTOMLE(c any, file string) error {
 	cnt, err := os.ReadFile(file)
 	if err != nil {
-		fmt.Printf("Failed to read TOML file: %s\n", file)
-		os.Exit(1)
+		return fmt.Errorf("failed to read TOML file %s: %w", file, err)
+	}
+
+	return toml.Unmarshal(cnt, c)
+}
+
+// LoadYAML set structure values to the YAML file content.
+func LoadYAML(c any, file string) error {
+	cnt, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read YAML file %s: %w", file, err)
+	}
+
+	return yaml.Unmarshal(cnt, c)
+}
+
+// LoadJSON set structure values to the JSON file content.
+func LoadJSON(c any, file string) error {
+	cnt, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read JSON file %s: %w", file, err)
+	}
+
+	return json.Unmarshal(cnt, c)
+}
+
+// LoadDotEnv parses a .env-style file (KEY=VALUE lines, "#" comments and
+// optionally single- or double-quoted values) and applies it to c through
+// the same field matching LoadEnv uses: a field's "env" tag if present,
+// otherwise its name, joined with "_" for nested structs.
+func LoadDotEnv(c any, file string) error {
+	cnt, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read .env file %s: %w", file, err)
+	}
+
+	return unmarshalDotEnv(cnt, c)
+}
+
+// unmarshalDotEnv is the Unmarshaler registered for ".env" in formats; it
+// holds LoadDotEnv's parsing and field matching once the file has already
+// been read, so RegisterFormat(".env", ...) can override it like any
+// other extension.
+func unmarshalDotEnv(data []byte, c any) error {
+	vars, err := parseDotEnv(data)
+	if err != nil {
+		return err
+	}
+
+	cb := func(name, kind string, tags reflect.StructTag, chain []string) string {
+		if k := tags.Get("env"); k != "" && k != "-" {
+			return vars[strings.ToUpper(k)]
+		}
+
+		n := strings.Join(chain, "_")
+		if n != "" {
+			n += "_"
+		}
+
+		return vars[strings.ToUpper(n+name)]
+	}
+
+	return scanE(c, []string{}, cb)
+}
+
+// parseDotEnv parses the content of a .env file into a key/value map.
+func parseDotEnv(cnt []byte) (map[string]string, error) {
+	vars := map[string]string{}
+
+	for n, line := range strings.Split(string(cnt), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing '='", n+1)
+		}
+
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+
+		switch {
+		case len(val) >= 2 && strings.HasPrefix(val, `"`) && strings.HasSuffix(val, `"`):
+			unquoted, err := strconv.Unquote(val)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			val = unquoted
+		case len(val) >= 2 && strings.HasPrefix(val, "'") && strings.HasSuffix(val, "'"):
+			val = val[1 : len(val)-1]
+		default:
+			if i := strings.Index(val, " #"); i >= 0 {
+				val = strings.TrimSpace(val[:i])
+			}
+		}
+
+		vars[strings.ToUpper(key)] = val
+	}
+
+	return vars, nil
+}
+
+// Unmarshaler parses raw file content into c. The built-in formats use
+// it internally; third parties can register additional ones with
+// RegisterFormat.
+type Unmarshaler func(data []byte, c any) error
+
+// formats maps a file extension (with leading dot) to the Unmarshaler
+// LoadFile uses for it.
+var formats = map[string]Unmarshaler{
+	".toml": toml.Unmarshal,
+	".yaml": yaml.Unmarshal,
+	".yml":  yaml.Unmarshal,
+	".json": json.Unmarshal,
+	".env":  unmarshalDotEnv,
+}
+
+// RegisterFormat registers fn as the Unmarshaler LoadFile uses for files
+// with the given extension (including the leading dot, e.g. ".ini"). It
+// overrides any existing registration for that extension, including the
+// built-in ones.
+func RegisterFormat(ext string, fn Unmarshaler) {
+	formats[ext] = fn
+}
+
+// LoadFile loads file into c, dispatching on its extension to the
+// Unmarshaler registered for it in formats: .toml, .yaml/.yml, .json and
+// .env out of the box, plus any extension registered via RegisterFormat
+// (which can also override a built-in one, including .env).
+func LoadFile(c any, file string) error {
+	ext := strings.ToLower(filepath.Ext(file))
+
+	fn, ok := formats[ext]
+	if !ok {
+		return fmt.Errorf("oneconf: no loader registered for extension %q", ext)
+	}
+
+	cnt, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", file, err)
+	}
+
+	return fn(cnt, c)
+}
+
+// WatchOptions configures WatchTOML's reloads, mirroring the EnvPrefix and
+// UseName the caller used for its initial LoadEnv/LoadFlags calls so a
+// reload doesn't silently stop honoring them.
+type WatchOptions struct {
+	EnvPrefix string
+	UseName   bool
+}
+
+// WatchTOML watches file for changes and keeps c in sync with its content.
+// Every time file is written, renamed or recreated (as editors do when
+// performing an "atomic save") it is re-parsed and LoadDefaults, the file
+// itself and LoadEnv/LoadFlags (using opts.EnvPrefix/opts.UseName) are
+// re-applied, in that order, to c under a mutex. A burst of events within
+// watchDebounce only triggers one reload. onChange is called after every
+// reload attempt with a non-nil error if the file could not be read or
+// parsed; c is left at its last good value in that case. The returned
+// stop func tears down the watcher goroutine.
+func WatchTOML(c any, file string, opts WatchOptions, onChange func(error)) (stop func(), err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(file)
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	done := make(chan struct{})
+
+	reload := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// load into a copy of c and only store it back on success, so a
+		// failed reload leaves c untouched.
+		next := reflect.New(reflect.TypeOf(c).Elem())
+		next.Elem().Set(reflect.ValueOf(c).Elem())
+		tmp := next.Interface()
+
+		if err := LoadDefaultsE(tmp); err != nil {
+			onChange(err)
+			return
+		}
+		if err := LoadTOMLE(tmp, file); err != nil {
+			onChange(err)
+			return
+		}
+		if err := LoadEnvE(tmp, opts.EnvPrefix, opts.UseName); err != nil {
+			onChange(err)
+			return
+		}
+		if err := LoadFlagsE(tmp, opts.UseName); err != nil {
+			onChange(err)
+			return
+		}
+
+		reflect.ValueOf(c).Elem().Set(next.Elem())
+		onChange(nil)
+	}
+
+	go func() {
+		var timer *time.Timer
+
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != filepath.Clean(file) {
+					continue
+				}
+				if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+					continue
+				}
+
+				if timer == nil {
+					timer = time.AfterFunc(watchDebounce, reload)
+				} else {
+					timer.Reset(watchDebounce)
+				}
+			case werr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				onChange(werr)
+			case <-done:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+		}
+	}()
+
+	stop = func() {
+		close(done)
+		watcher.Close()
 	}
 
-	toml.Unmarshal(cnt, c)
+	return stop, nil
 }
 
 // LoadEnv set fields to a value define by a environment variable
 // we test variables named after prefix + (tag "env" or field name)
 func LoadEnv(c any, prefix string, useName bool) {
+	if err := LoadEnvE(c, prefix, useName); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// LoadEnvE is like LoadEnv but returns an error instead of exiting.
+func LoadEnvE(c any, prefix string, useName bool) error {
 	cb := func(name, kind string, tags reflect.StructTag, chain []string) string {
 		if k := tags.Get("env"); k != "" && k != "-" {
 			return os.Getenv(strings.ToUpper(prefix + k))
@@ -135,13 +549,54 @@ func LoadEnv(c any, prefix string, useName bool) {
 		return ""
 	}
 
-	scan(c, []string{}, cb)
+	return scanE(c, []string{}, cb)
 }
 
 // LoadFlags set structure with values from the command line
 func LoadFlags(c any, useName bool) {
+	if err := LoadFlagsE(c, useName); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
 
-	bools, vals, _ := ParseCommand(os.Args[1:])
+// LoadFlagsE is like LoadFlags but returns an error instead of exiting.
+func LoadFlagsE(c any, useName bool) error {
+	return loadFlagsArgsE(c, useName, os.Args[1:])
+}
+
+// loadFlagsArgsE is the shared implementation behind LoadFlagsE and Load,
+// parsing args instead of always reading os.Args[1:].
+func loadFlagsArgsE(c any, useName bool, args []string) error {
+	bools, vals, _ := ParseCommand(args)
+	return loadFlagsParsedE(c, useName, bools, vals)
+}
+
+// loadFlagsParsedE is the shared implementation behind loadFlagsArgsE and
+// Dispatch, applying an already-parsed set of bools/vals instead of
+// parsing os.Args or another slice itself.
+func loadFlagsParsedE(c any, useName bool, bools map[string]bool, vals map[string][]string) error {
+
+	// flagVal returns the value to feed scanE for a flag seen under key k:
+	// the joined repeats (using the field's "sep" tag) for slice fields,
+	// or the last occurrence for every other kind, matching the historical
+	// last-one-wins behavior.
+	flagVal := func(k, kind string, tags reflect.StructTag) string {
+		v := vals[k]
+		if len(v) == 0 {
+			return ""
+		}
+		if kind != "slice" {
+			return v[len(v)-1]
+		}
+
+		sep := tags.Get("sep")
+		if sep == "" {
+			sep = ","
+		}
+
+		return strings.Join(v, sep)
+	}
 
 	cb := func(name, kind string, tags reflect.StructTag, chain []string) string {
 
@@ -151,7 +606,7 @@ func LoadFlags(c any, useName bool) {
 				return "true"
 			}
 
-			if v := vals[k]; v != "" {
+			if v := flagVal(k, kind, tags); v != "" {
 				return v
 			}
 		}
@@ -163,7 +618,7 @@ func LoadFlags(c any, useName bool) {
 					return "true"
 				}
 
-				if v := vals[k]; v != "" {
+				if v := flagVal(k, kind, tags); v != "" {
 					return v
 				}
 			}
@@ -180,7 +635,7 @@ func LoadFlags(c any, useName bool) {
 					return "true"
 				}
 
-				if v := vals[n]; v != "" {
+				if v := flagVal(n, kind, tags); v != "" {
 					return v
 				}
 			}
@@ -189,11 +644,112 @@ func LoadFlags(c any, useName bool) {
 		return ""
 	}
 
-	scan(c, []string{}, cb)
+	return scanE(c, []string{}, cb)
+}
+
+// LoadOptions configures Load.
+type LoadOptions struct {
+	// Name derives the default config file search path when File is
+	// empty: "./<Name><ext>", "$XDG_CONFIG_HOME/<Name>/<Name><ext>" and
+	// "/etc/<Name>/<Name><ext>", in that order, trying each built-in
+	// extension (.toml, .yaml, .yml, .json, .env) at every location;
+	// extensions added with RegisterFormat are not tried. Left empty, no
+	// config file is auto-discovered.
+	Name string
+
+	// File, when set, is loaded instead of auto-discovering one from Name.
+	File string
+
+	// EnvPrefix is prepended to every environment variable name.
+	EnvPrefix string
+
+	// UseName enables falling back to field-name-derived env vars and flags.
+	UseName bool
+
+	// Args overrides os.Args[1:] for flag parsing when non-nil.
+	Args []string
+}
+
+// Load composes LoadDefaults, a config file, LoadEnv and LoadFlags into a
+// single call with a well-defined precedence: defaults, then the config
+// file, then environment variables, then command-line flags, each
+// overriding the previous. Unlike the individual loaders it returns an
+// error instead of exiting the process.
+func Load(c any, opts LoadOptions) error {
+	if err := LoadDefaultsE(c); err != nil {
+		return err
+	}
+
+	file := opts.File
+	if file == "" {
+		file = findConfigFile(opts.Name)
+	}
+	if file != "" {
+		if err := LoadFile(c, file); err != nil {
+			return err
+		}
+	}
+
+	if err := LoadEnvE(c, opts.EnvPrefix, opts.UseName); err != nil {
+		return err
+	}
+
+	args := opts.Args
+	if args == nil {
+		args = os.Args[1:]
+	}
+
+	return loadFlagsArgsE(c, opts.UseName, args)
+}
+
+// configExts lists the file extensions findConfigFile tries, in order of
+// preference, for every candidate base path.
+var configExts = []string{".toml", ".yaml", ".yml", ".json", ".env"}
+
+// findConfigFile returns the first of the well-known config paths for name
+// that exists on disk, or "" if none does.
+func findConfigFile(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	bases := []string{filepath.Join(".", name)}
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		bases = append(bases, filepath.Join(xdg, name, name))
+	}
+
+	bases = append(bases, filepath.Join("/etc", name, name))
+
+	for _, base := range bases {
+		for _, ext := range configExts {
+			if _, err := os.Stat(base + ext); err == nil {
+				return base + ext
+			}
+		}
+	}
+
+	return ""
 }
 
-// GenerateHelp returns a string with help information
+// GenerateHelp returns a string with help information. If commands have
+// been registered with RegisterCommand, it is followed by a section per
+// command, each rendering that command's own flags the same way.
 func GenerateHelp(c any, prefix string, useName, showShort, showLong, showEnv bool) string {
+	help := generateHelp(c, prefix, useName, showShort, showLong, showEnv)
+
+	for _, rc := range commands {
+		help += fmt.Sprintf("\n%s:\n", rc.name)
+		help += generateHelp(rc.cfg, prefix, useName, showShort, showLong, showEnv)
+	}
+
+	return help
+}
+
+// generateHelp renders the help text for a single config struct; it is
+// the shared implementation behind GenerateHelp's main section and its
+// per-command sections.
+func generateHelp(c any, prefix string, useName, showShort, showLong, showEnv bool) string {
 
 	type op struct {
 		help  string
@@ -294,7 +850,8 @@ func IsAskingForHelp() bool {
 	return b["h"] || b["help"]
 }
 
-// GetArg will return the value of a short line argument -c=VAL, -c by itself is true or empty
+// GetArg will return the value of a short line argument -c=VAL, -c by itself is true or empty.
+// If the argument was repeated, the last occurrence wins.
 func GetArg(name string) string {
 	b, m, _ := ParseCommand(os.Args[1:])
 	for k := range b {
@@ -303,21 +860,89 @@ func GetArg(name string) string {
 		}
 	}
 	for k, v := range m {
-		if k == name {
-			return v
+		if k == name && len(v) > 0 {
+			return v[len(v)-1]
 		}
 	}
 	return ""
 }
 
-// ParseCommand will parse an []string to brake it into a list of booleans, a list of arguments and
-// a map of key:value
-func ParseCommand(in []string) (bools map[string]bool, vals map[string]string, args []string) {
+// registeredCommand pairs a subcommand name with the config struct
+// RegisterCommand should populate for it.
+type registeredCommand struct {
+	name string
+	cfg  any
+}
+
+// commands holds every subcommand registered with RegisterCommand, in
+// registration order.
+var commands []registeredCommand
+
+// RegisterCommand registers cfg as the config struct for the subcommand
+// name, so that Dispatch can route a matching invocation (e.g. "app name
+// --flag value") to it.
+func RegisterCommand(name string, cfg any) {
+	for i, rc := range commands {
+		if rc.name == name {
+			commands[i].cfg = cfg
+			return
+		}
+	}
+
+	commands = append(commands, registeredCommand{name: name, cfg: cfg})
+}
+
+// Dispatch inspects os.Args[1:], takes its first non-flag token as the
+// command name, and applies the remaining flags to that command's config
+// struct registered with RegisterCommand, the same way LoadFlags(cfg,
+// false) would. It returns the chosen command name, any positional tokens
+// left over after the command and its flags (e.g. a nested verb like "up"
+// in "app migrate up --dry-run", for the caller to route itself), and an
+// error if no command was given or if it was not registered.
+func Dispatch() (cmd string, args []string, err error) {
+	bools, vals, cmd, args := ParseCommandWithCmd(os.Args[1:])
+	if cmd == "" {
+		return "", nil, fmt.Errorf("oneconf: no command given")
+	}
+
+	for _, rc := range commands {
+		if rc.name == cmd {
+			return cmd, args, loadFlagsParsedE(rc.cfg, false, bools, vals)
+		}
+	}
+
+	return cmd, args, fmt.Errorf("oneconf: unknown command %q", cmd)
+}
+
+// ParseCommand will parse an []string to brake it into a list of booleans, a
+// map of key to every value it was given (in order, so repeated flags like
+// "--tag a --tag b" are not lost to overwriting) and any positional tokens
+// or ones following a literal "--" (args).
+func ParseCommand(in []string) (bools map[string]bool, vals map[string][]string, args []string) {
+	bools, vals, _, args = parseArgs(in, false)
+	return bools, vals, args
+}
+
+// ParseCommandWithCmd is like ParseCommand but additionally takes the first
+// positional token seen before a literal "--" as a subcommand name (cmd), so
+// Dispatch can route on it; a "--" with no preceding positional token means
+// no command was given, matching ParseCommand's own treatment of "--" as
+// "everything after this is literal args, not something to parse as a
+// command".
+func ParseCommandWithCmd(in []string) (bools map[string]bool, vals map[string][]string, cmd string, args []string) {
+	return parseArgs(in, true)
+}
+
+// parseArgs is the shared implementation behind ParseCommand and
+// ParseCommandWithCmd. When splitCmd is true, the first positional token
+// seen before a literal "--" is split off into cmd instead of being kept in
+// args.
+func parseArgs(in []string, splitCmd bool) (bools map[string]bool, vals map[string][]string, cmd string, args []string) {
 
 	i := 0
 
 	bools = make(map[string]bool)
-	vals = make(map[string]string)
+	vals = make(map[string][]string)
 
 	for {
 		if i >= len(in) {
@@ -337,7 +962,8 @@ func ParseCommand(in []string) (bools map[string]bool, vals map[string]string, a
 			if (i+1) >= len(in) || strings.HasPrefix(in[i+1], "-") {
 				bools[in[i][2:]] = true
 			} else {
-				vals[in[i][2:]] = in[i+1]
+				k := in[i][2:]
+				vals[k] = append(vals[k], in[i+1])
 				i++
 			}
 		} else if strings.HasPrefix(in[i], "-") {
@@ -346,13 +972,16 @@ func ParseCommand(in []string) (bools map[string]bool, vals map[string]string, a
 					if (i+1) >= len(in) || strings.HasPrefix(in[i+1], "-") {
 						bools[string(f)] = true
 					} else {
-						vals[string(f)] = in[i+1]
+						k := string(f)
+						vals[k] = append(vals[k], in[i+1])
 						i++
 					}
 					break
 				}
 				bools[string(f)] = true
 			}
+		} else if splitCmd && cmd == "" {
+			cmd = in[i]
 		} else {
 			args = append(args, in[i])
 		}
@@ -360,7 +989,5 @@ func ParseCommand(in []string) (bools map[string]bool, vals map[string]string, a
 		i++
 	}
 
-	// fmt.Println(bools, vals, args)
-
-	return bools, vals, args
+	return bools, vals, cmd, args
 }
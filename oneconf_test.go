@@ -3,6 +3,8 @@ package oneconf
 import (
 	"fmt"
 	"os"
+	"testing"
+	"time"
 )
 
 func ExampleLoadDefaults() {
@@ -34,6 +36,72 @@ func ExampleLoadDefaults() {
 	// Output: {false true true true false 12 -12 70 70 112 56 19 0.012 120 text}
 }
 
+func ExampleLoadDefaults_collections() {
+
+	type cfg struct {
+		Tags    []string          `default:"a,b,c"`
+		Ports   []int             `default:"80,443"`
+		Weights []float64         `default:"1.5,2.5"`
+		Labels  map[string]string `default:"env=prod,region=us"`
+		Timeout time.Duration     `default:"1h30m"`
+		Start   time.Time         `default:"2024-01-02T15:04:05Z"`
+	}
+
+	c := cfg{}
+
+	LoadDefaults(&c)
+
+	fmt.Println(c.Tags, c.Ports, c.Weights, c.Labels, c.Timeout, c.Start.Format(time.RFC3339))
+
+	// Output: [a b c] [80 443] [1.5 2.5] map[env:prod region:us] 1h30m0s 2024-01-02T15:04:05Z
+}
+
+func ExampleLoadDefaults_collectionsSep() {
+
+	type cfg struct {
+		Tags   []string          `default:"a;b;c" sep:";"`
+		Labels map[string]string `default:"env=prod;region=us" sep:";"`
+	}
+
+	c := cfg{}
+
+	LoadDefaults(&c)
+
+	fmt.Println(c.Tags, c.Labels)
+
+	// Output: [a b c] map[env:prod region:us]
+}
+
+func ExampleLoadDefaults_collectionsError() {
+
+	type badSlice struct {
+		Ports []int `default:"80,not-a-number"`
+	}
+
+	type badMap struct {
+		Labels map[string]string `default:"env=prod,broken"`
+	}
+
+	type badDuration struct {
+		Timeout time.Duration `default:"not-a-duration"`
+	}
+
+	type badTime struct {
+		Start time.Time `default:"not-a-time"`
+	}
+
+	fmt.Println(LoadDefaultsE(&badSlice{}))
+	fmt.Println(LoadDefaultsE(&badMap{}))
+	fmt.Println(LoadDefaultsE(&badDuration{}))
+	fmt.Println(LoadDefaultsE(&badTime{}))
+
+	// Output:
+	// Ports: invalid value "80,not-a-number" for []int
+	// Labels: invalid value "env=prod,broken" for map[string]string
+	// Timeout: invalid value "not-a-duration" for time.Duration
+	// Start: invalid value "not-a-time" for time.Time
+}
+
 func ExampleLoadEnv() {
 	type cfg struct {
 		A bool
@@ -123,6 +191,188 @@ F = 12e-1
 	// Output: {my-string 272 [one two] {true 1.2}}
 }
 
+func ExampleLoadYAML() {
+
+	type cfg struct {
+		A string
+		B int
+		D struct {
+			E bool
+			F float64
+		}
+	}
+
+	yml := `
+a: my-string
+b: 272
+d:
+  e: true
+  f: 1.2
+`
+
+	if err := os.WriteFile("_$_test.yaml", []byte(yml), 0o600); err != nil {
+		fmt.Println("can not save test file")
+		os.Exit(1)
+	}
+
+	c := cfg{}
+
+	if err := LoadYAML(&c, "_$_test.yaml"); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Print(c)
+
+	if err := os.Remove("_$_test.yaml"); err != nil {
+		fmt.Println("can not remove test file")
+		os.Exit(1)
+	}
+
+	// Output: {my-string 272 {true 1.2}}
+}
+
+func ExampleLoadJSON() {
+
+	type cfg struct {
+		A string
+		B int
+		D struct {
+			E bool
+			F float64
+		}
+	}
+
+	js := `{"A": "my-string", "B": 272, "D": {"E": true, "F": 1.2}}`
+
+	if err := os.WriteFile("_$_test.json", []byte(js), 0o600); err != nil {
+		fmt.Println("can not save test file")
+		os.Exit(1)
+	}
+
+	c := cfg{}
+
+	if err := LoadJSON(&c, "_$_test.json"); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Print(c)
+
+	if err := os.Remove("_$_test.json"); err != nil {
+		fmt.Println("can not remove test file")
+		os.Exit(1)
+	}
+
+	// Output: {my-string 272 {true 1.2}}
+}
+
+func ExampleLoadDotEnv() {
+
+	type cfg struct {
+		A string `env:"MY_A"`
+		B int
+	}
+
+	env := `
+# a comment
+MY_A="my-string"
+B=272
+`
+
+	if err := os.WriteFile("_$_test.env", []byte(env), 0o600); err != nil {
+		fmt.Println("can not save test file")
+		os.Exit(1)
+	}
+
+	c := cfg{}
+
+	if err := LoadDotEnv(&c, "_$_test.env"); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Print(c)
+
+	if err := os.Remove("_$_test.env"); err != nil {
+		fmt.Println("can not remove test file")
+		os.Exit(1)
+	}
+
+	// Output: {my-string 272}
+}
+
+func ExampleLoadFile() {
+
+	type cfg struct {
+		A string
+		B int
+	}
+
+	if err := os.WriteFile("_$_test.json", []byte(`{"A": "my-string", "B": 272}`), 0o600); err != nil {
+		fmt.Println("can not save test file")
+		os.Exit(1)
+	}
+
+	c := cfg{}
+
+	if err := LoadFile(&c, "_$_test.json"); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Print(c)
+
+	if err := os.Remove("_$_test.json"); err != nil {
+		fmt.Println("can not remove test file")
+		os.Exit(1)
+	}
+
+	// Output: {my-string 272}
+}
+
+func ExampleRegisterFormat() {
+
+	type cfg struct {
+		A string
+	}
+
+	// a stub format, plus an override of a built-in one, both keyed by
+	// extension; LoadFile must dispatch to the registered fn, not the
+	// built-in TOML/".env" handling.
+	RegisterFormat(".custom", func(data []byte, c any) error {
+		c.(*cfg).A = "from-custom:" + string(data)
+		return nil
+	})
+	RegisterFormat(".env", func(data []byte, c any) error {
+		c.(*cfg).A = "from-override:" + string(data)
+		return nil
+	})
+
+	if err := os.WriteFile("_$_test.custom", []byte("payload"), 0o600); err != nil {
+		fmt.Println("can not save test file")
+		os.Exit(1)
+	}
+	if err := os.WriteFile("_$_test.env", []byte("A=ignored"), 0o600); err != nil {
+		fmt.Println("can not save test file")
+		os.Exit(1)
+	}
+
+	c := cfg{}
+	if err := LoadFile(&c, "_$_test.custom"); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(c.A)
+
+	c = cfg{}
+	if err := LoadFile(&c, "_$_test.env"); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(c.A)
+
+	os.Remove("_$_test.custom")
+	os.Remove("_$_test.env")
+
+	// restore the built-in .env handling so it doesn't leak into other tests.
+	RegisterFormat(".env", unmarshalDotEnv)
+
+	// Output:
+	// from-custom:payload
+	// from-override:A=ignored
+}
+
 func ExampleLoadFlags() {
 
 	type cfg struct {
@@ -155,3 +405,194 @@ func ExampleLoadFlags() {
 
 	// Outputs:
 }
+
+func ExampleLoadFlags_slice() {
+
+	type cfg struct {
+		Tags []string `long:"tag"`
+	}
+
+	c := cfg{}
+
+	os.Args = []string{"binary", "--tag", "a", "--tag", "b"}
+
+	LoadFlags(&c, false)
+
+	fmt.Println(c.Tags)
+
+	// Output: [a b]
+}
+
+func ExampleLoad() {
+
+	type cfg struct {
+		A string `default:"fallback"`
+		B int    `env:"B"`
+	}
+
+	os.Setenv("PRE_B", "42")
+	os.Args = []string{"binary"}
+
+	c := cfg{}
+
+	if err := Load(&c, LoadOptions{EnvPrefix: "PRE_"}); err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Println(c)
+
+	// Output: {fallback 42}
+}
+
+func ExampleLoad_error() {
+
+	type cfg struct {
+		F int8 `default:"not-a-number"`
+	}
+
+	c := cfg{}
+
+	err := Load(&c, LoadOptions{})
+	fmt.Println(err)
+
+	// Output: F: invalid value "not-a-number" for int8
+}
+
+func ExampleLoad_errorNestedPath() {
+
+	type Inner2 struct {
+		F int8 `default:"not-a-number"`
+	}
+
+	type Inner1 struct {
+		E Inner2
+	}
+
+	type cfg struct {
+		D Inner1
+	}
+
+	c := cfg{}
+
+	err := Load(&c, LoadOptions{})
+	fmt.Println(err)
+
+	// Output: D.E.F: invalid value "not-a-number" for int8
+}
+
+func ExampleDispatch() {
+
+	type serveCfg struct {
+		Port int `long:"port"`
+	}
+
+	type migrateCfg struct {
+		DryRun bool `long:"dry-run"`
+	}
+
+	serve := serveCfg{}
+	migrate := migrateCfg{}
+
+	RegisterCommand("serve", &serve)
+	RegisterCommand("migrate", &migrate)
+
+	os.Args = []string{"app", "serve", "--port", "8080"}
+
+	cmd, args, err := Dispatch()
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Println(cmd, args, serve)
+
+	// a nested verb (e.g. "up" vs "down") is left over in args for the
+	// caller to route itself, rather than being silently discarded.
+	os.Args = []string{"app", "migrate", "up", "--dry-run"}
+
+	cmd, args, err = Dispatch()
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Println(cmd, args, migrate)
+
+	// Output:
+	// serve [] {8080}
+	// migrate [up] {true}
+}
+
+func ExampleParseCommand() {
+
+	// ParseCommand keeps every positional token in args, unlike
+	// ParseCommandWithCmd which splits the first one off as a subcommand.
+	bools, vals, args := ParseCommand([]string{"serve", "-h", "--port", "8080", "extra"})
+
+	fmt.Println(bools, vals, args)
+
+	// Output: map[h:true] map[port:[8080]] [serve extra]
+}
+
+func TestWatchTOML(t *testing.T) {
+
+	type cfg struct {
+		A string
+	}
+
+	file := "_$_watch_test.toml"
+
+	if err := os.WriteFile(file, []byte(`A = "one"`), 0o600); err != nil {
+		t.Fatalf("can not save test file: %v", err)
+	}
+	defer os.Remove(file)
+
+	c := cfg{}
+	if err := LoadTOMLE(&c, file); err != nil {
+		t.Fatalf("initial load failed: %v", err)
+	}
+
+	changes := make(chan error, 4)
+
+	stop, err := WatchTOML(&c, file, WatchOptions{}, func(err error) {
+		changes <- err
+	})
+	if err != nil {
+		t.Fatalf("WatchTOML failed: %v", err)
+	}
+	defer stop()
+
+	if err := os.WriteFile(file, []byte(`A = "two"`), 0o600); err != nil {
+		t.Fatalf("can not update test file: %v", err)
+	}
+
+	select {
+	case err := <-changes:
+		if err != nil {
+			t.Fatalf("unexpected reload error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+
+	if c.A != "two" {
+		t.Fatalf("expected A to be reloaded to %q, got %q", "two", c.A)
+	}
+
+	// an unterminated string is malformed TOML; the reload should report
+	// an error through onChange and leave c at its last good value.
+	if err := os.WriteFile(file, []byte(`A = "three`), 0o600); err != nil {
+		t.Fatalf("can not write malformed test file: %v", err)
+	}
+
+	select {
+	case err := <-changes:
+		if err == nil {
+			t.Fatal("expected a reload error for malformed TOML, got nil")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reload error")
+	}
+
+	if c.A != "two" {
+		t.Fatalf("expected c to keep its last good value %q, got %q", "two", c.A)
+	}
+}